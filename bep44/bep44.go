@@ -0,0 +1,114 @@
+// Package bep44 implements BEP 44 (http://bittorrent.org/beps/bep_0044.html),
+// which lets DHT nodes store and retrieve arbitrary values. Values are either
+// immutable, and addressed by the SHA-1 hash of their bencoded form, or
+// mutable, and addressed by an Ed25519 public key plus an optional salt.
+//
+// Target derivation, signing/verification and the Store's CAS/sequence
+// enforcement are all self-contained. Server.Get and Server.Put drive the
+// actual network operation: they perform the iterative lookup and token
+// collection via a supplied Lookup, then issue the get/put queries
+// themselves via a supplied Querier, so this package doesn't need to own
+// node communication or routing table maintenance to be usable end to end.
+package bep44
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha1"
+	"fmt"
+
+	"github.com/anacrolix/torrent/bencode"
+)
+
+// MaxValueSize is the largest bencoded value BEP 44 allows a node to store.
+const MaxValueSize = 1000
+
+// MaxSaltSize is the largest salt BEP 44 allows.
+const MaxSaltSize = 64
+
+// Item is a single BEP 44 value. Immutable items have K, Sig, Seq and Salt
+// all nil/empty; mutable items have at least K and Sig set.
+type Item struct {
+	V    interface{}
+	K    *[32]byte
+	Sig  *[64]byte
+	Seq  *int64
+	Salt []byte
+}
+
+// Mutable reports whether the item is signed, as opposed to addressed by the
+// hash of its value.
+func (i Item) Mutable() bool {
+	return i.K != nil
+}
+
+// Target returns the 20-byte key the item is stored and looked up under.
+func (i Item) Target() (target [20]byte, err error) {
+	if i.Mutable() {
+		return MutableTarget(*i.K, i.Salt), nil
+	}
+	b, err := bencode.Marshal(i.V)
+	if err != nil {
+		return target, fmt.Errorf("bencoding v: %w", err)
+	}
+	return sha1.Sum(b), nil
+}
+
+// MutableTarget derives the target for a mutable item: sha1(pubkey || salt).
+func MutableTarget(k [32]byte, salt []byte) (target [20]byte) {
+	h := sha1.New()
+	h.Write(k[:])
+	h.Write(salt)
+	copy(target[:], h.Sum(nil))
+	return target
+}
+
+// ImmutableTarget derives the target for an immutable item: sha1(bencode(v)).
+func ImmutableTarget(v interface{}) (target [20]byte, err error) {
+	b, err := bencode.Marshal(v)
+	if err != nil {
+		return target, fmt.Errorf("bencoding v: %w", err)
+	}
+	return sha1.Sum(b), nil
+}
+
+// signatureInput builds the buffer that is signed and verified for a mutable
+// item, per BEP 44: (salt? + "3:seqi<seq>e1:v<bencoded v>"), with the salt
+// and seq fragments in the same sorted-key order a bencoded dict would use.
+func signatureInput(salt []byte, seq int64, v interface{}) ([]byte, error) {
+	bv, err := bencode.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("bencoding v: %w", err)
+	}
+	var buf bytes.Buffer
+	if len(salt) > 0 {
+		fmt.Fprintf(&buf, "4:salt%d:", len(salt))
+		buf.Write(salt)
+	}
+	fmt.Fprintf(&buf, "3:seqi%de1:v", seq)
+	buf.Write(bv)
+	return buf.Bytes(), nil
+}
+
+// Sign produces the signature for a mutable item with the given salt,
+// sequence number and value.
+func Sign(priv ed25519.PrivateKey, salt []byte, seq int64, v interface{}) (sig [64]byte, err error) {
+	in, err := signatureInput(salt, seq, v)
+	if err != nil {
+		return sig, err
+	}
+	copy(sig[:], ed25519.Sign(priv, in))
+	return sig, nil
+}
+
+// Verify checks that sig is a valid signature of (salt, seq, v) under pub.
+func Verify(pub [32]byte, salt []byte, seq int64, v interface{}, sig [64]byte) error {
+	in, err := signatureInput(salt, seq, v)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub[:], in, sig[:]) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
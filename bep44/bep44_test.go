@@ -0,0 +1,51 @@
+package bep44
+
+import (
+	"crypto/ed25519"
+	"testing"
+)
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pubArr [32]byte
+	copy(pubArr[:], pub)
+
+	salt := []byte("salt")
+	const seq = int64(7)
+	v := "hello world"
+
+	sig, err := Sign(priv, salt, seq, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(pubArr, salt, seq, v, sig); err != nil {
+		t.Fatalf("Verify of a correctly signed item failed: %v", err)
+	}
+
+	if err := Verify(pubArr, salt, seq+1, v, sig); err == nil {
+		t.Fatal("Verify succeeded with the wrong seq")
+	}
+	if err := Verify(pubArr, []byte("other salt"), seq, v, sig); err == nil {
+		t.Fatal("Verify succeeded with the wrong salt")
+	}
+	if err := Verify(pubArr, salt, seq, "tampered", sig); err == nil {
+		t.Fatal("Verify succeeded with a tampered value")
+	}
+}
+
+func TestMutableTargetDependsOnSalt(t *testing.T) {
+	var k [32]byte
+	copy(k[:], []byte("01234567890123456789012345678901"))
+
+	t1 := MutableTarget(k, []byte("a"))
+	t2 := MutableTarget(k, []byte("b"))
+	if t1 == t2 {
+		t.Fatal("targets for different salts must differ")
+	}
+	if t1 != MutableTarget(k, []byte("a")) {
+		t.Fatal("MutableTarget must be deterministic")
+	}
+}
@@ -0,0 +1,40 @@
+package bep44
+
+import (
+	"fmt"
+
+	"github.com/yarikk/dht/krpc"
+)
+
+// Error is a BEP 44 protocol error: one of krpc's BEP 44 error codes paired
+// with a message, letting callers distinguish failure modes such as a CAS
+// mismatch from a bad signature without parsing the KRPC error string. It
+// reuses krpc.ErrorCode rather than declaring its own, so there's a single
+// registry of codes to match on.
+type Error struct {
+	Code krpc.ErrorCode
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("bep44: %s (%d)", e.Msg, e.Code)
+}
+
+// ToKRPCError converts e to the krpc.Error that should be sent back over
+// the wire in an error reply.
+func (e *Error) ToKRPCError() *krpc.Error {
+	return krpc.NewError(e.Code, e.Msg)
+}
+
+func newError(code krpc.ErrorCode, msg string) *Error {
+	return &Error{Code: code, Msg: msg}
+}
+
+// The standard BEP 44 errors, returned by HandlePut, HandleCAS and Verify.
+var (
+	ErrValueTooBig      = newError(krpc.ErrorCodeValueTooBig, "message (v field) too big")
+	ErrInvalidSignature = newError(krpc.ErrorCodeInvalidSignature, "invalid signature")
+	ErrSaltTooBig       = newError(krpc.ErrorCodeSaltTooBig, "salt too big")
+	ErrCASMismatch      = newError(krpc.ErrorCodeCASMismatch, "cas mismatch")
+	ErrSeqRegression    = newError(krpc.ErrorCodeSeqLessThanCurrent, "sequence number less than current")
+)
@@ -0,0 +1,43 @@
+package bep44
+
+import "github.com/yarikk/dht/krpc"
+
+// PutArgs builds the "a" arguments for a put query carrying item, to be sent
+// with the token obtained from an earlier get/get_peers response. cas, if
+// non-nil, asks the receiving node to reject the put unless its currently
+// stored seq for item's target matches.
+func PutArgs(id krpc.ID, token string, item Item, cas *int64) krpc.MsgArgs {
+	return krpc.MsgArgs{
+		ID:    id,
+		Token: token,
+		V:     item.V,
+		K:     item.K,
+		Sig:   item.Sig,
+		Seq:   item.Seq,
+		Cas:   cas,
+		Salt:  item.Salt,
+	}
+}
+
+// GetArgs builds the "a" arguments for a get query for target.
+func GetArgs(id krpc.ID, target krpc.ID, seq *int64) krpc.MsgArgs {
+	return krpc.MsgArgs{
+		ID:     id,
+		Target: target,
+		Seq:    seq,
+	}
+}
+
+// ItemFromReturn extracts the Item carried by a get response, along with
+// whether the response actually carried a value at all.
+func ItemFromReturn(r krpc.Return) (Item, bool) {
+	if r.V == nil {
+		return Item{}, false
+	}
+	return Item{
+		V:   r.V,
+		K:   r.K,
+		Sig: r.Sig,
+		Seq: r.Seq,
+	}, true
+}
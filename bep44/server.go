@@ -0,0 +1,133 @@
+package bep44
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yarikk/dht/krpc"
+)
+
+// TokenizedNode is a node found during a lookup, paired with the announce
+// token it handed back when queried.
+type TokenizedNode struct {
+	Node  krpc.NodeInfo
+	Token string
+}
+
+// Lookup performs the iterative traversal BEP 44 needs before a get or put:
+// finding the nodes closest to a target, and the token each one returned
+// when queried along the way. Server calls it once per Get/Put and treats
+// every node it returns as a candidate to query.
+type Lookup interface {
+	FindClosest(ctx context.Context, target krpc.ID) ([]TokenizedNode, error)
+}
+
+// Querier issues the two BEP 44 query types against a single node.
+type Querier interface {
+	Get(ctx context.Context, node krpc.NodeInfo, args krpc.MsgArgs) (krpc.Return, error)
+	Put(ctx context.Context, node krpc.NodeInfo, args krpc.MsgArgs) error
+}
+
+// Server performs BEP 44 get/put operations over the network: it drives
+// Lookup for the iterative traversal and token collection, and Querier for
+// the wire queries themselves.
+type Server struct {
+	ID      krpc.ID
+	Lookup  Lookup
+	Querier Querier
+}
+
+// Get performs an iterative lookup for target, queries get against every
+// node it finds, and returns the item with the highest seq seen (the most
+// recent mutable value, or the sole immutable one). found is false if no
+// node returned a value. A candidate is discarded, rather than trusted,
+// unless it actually verifies against target: a mutable candidate must
+// carry a signature that verifies under its own K/salt/seq, and its K/salt
+// must themselves hash to target; an immutable candidate's bencoded V must
+// hash to target. This is what stops a node from returning arbitrary data
+// for someone else's target. Get takes no salt and a get response carries
+// none, so mutable candidates are verified with an empty salt: an item
+// stored under a non-empty salt is never found.
+func (s *Server) Get(ctx context.Context, target krpc.ID) (item Item, found bool, err error) {
+	nodes, err := s.Lookup.FindClosest(ctx, target)
+	if err != nil {
+		return Item{}, false, fmt.Errorf("looking up %x: %w", target, err)
+	}
+	for _, n := range nodes {
+		r, err := s.Querier.Get(ctx, n.Node, GetArgs(s.ID, target, nil))
+		if err != nil {
+			continue
+		}
+		got, ok := ItemFromReturn(r)
+		if !ok || !verifyCandidate(target, got) {
+			continue
+		}
+		if !found || seqGreater(got, item) {
+			item, found = got, true
+		}
+	}
+	return item, found, nil
+}
+
+// verifyCandidate reports whether item is a legitimately addressed value
+// for target, as opposed to arbitrary data a misbehaving or malicious node
+// handed back in response to a get.
+func verifyCandidate(target krpc.ID, item Item) bool {
+	if item.Mutable() {
+		if item.Sig == nil {
+			return false
+		}
+		var seq int64
+		if item.Seq != nil {
+			seq = *item.Seq
+		}
+		if err := Verify(*item.K, item.Salt, seq, item.V, *item.Sig); err != nil {
+			return false
+		}
+		return krpc.ID(MutableTarget(*item.K, item.Salt)) == target
+	}
+	got, err := ImmutableTarget(item.V)
+	if err != nil {
+		return false
+	}
+	return krpc.ID(got) == target
+}
+
+func seqGreater(a, b Item) bool {
+	if a.Seq == nil {
+		return false
+	}
+	if b.Seq == nil {
+		return true
+	}
+	return *a.Seq > *b.Seq
+}
+
+// Put stores item at its own target: it performs a lookup to find the
+// closest nodes and collect an announce token from each, then sends a put
+// carrying item (and cas, if non-nil) to every one of them, announce_peer-
+// style. It returns the number of nodes that accepted the put, and an error
+// only if none did.
+func (s *Server) Put(ctx context.Context, item Item, cas *int64) (stored int, err error) {
+	target, err := item.Target()
+	if err != nil {
+		return 0, err
+	}
+	nodes, err := s.Lookup.FindClosest(ctx, target)
+	if err != nil {
+		return 0, fmt.Errorf("looking up %x: %w", target, err)
+	}
+	var lastErr error
+	for _, n := range nodes {
+		args := PutArgs(s.ID, n.Token, item, cas)
+		if err := s.Querier.Put(ctx, n.Node, args); err != nil {
+			lastErr = err
+			continue
+		}
+		stored++
+	}
+	if stored == 0 && lastErr != nil {
+		return 0, lastErr
+	}
+	return stored, nil
+}
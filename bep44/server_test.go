@@ -0,0 +1,178 @@
+package bep44
+
+import (
+	"context"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/yarikk/dht/krpc"
+)
+
+type fakeLookup struct {
+	nodes []TokenizedNode
+	err   error
+}
+
+func (l *fakeLookup) FindClosest(ctx context.Context, target krpc.ID) ([]TokenizedNode, error) {
+	return l.nodes, l.err
+}
+
+type fakeQuerier struct {
+	// returns, keyed by node ID, to hand back from Get.
+	returns map[krpc.ID]krpc.Return
+}
+
+func (q *fakeQuerier) Get(ctx context.Context, node krpc.NodeInfo, args krpc.MsgArgs) (krpc.Return, error) {
+	return q.returns[node.ID], nil
+}
+
+func (q *fakeQuerier) Put(ctx context.Context, node krpc.NodeInfo, args krpc.MsgArgs) error {
+	return nil
+}
+
+func node(n byte) krpc.NodeInfo {
+	var id krpc.ID
+	id[0] = n
+	return krpc.NodeInfo{ID: id}
+}
+
+func signedMutableReturn(t *testing.T, salt []byte, seq int64, v interface{}) (krpc.Return, [32]byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pubArr [32]byte
+	copy(pubArr[:], pub)
+	sig, err := Sign(priv, salt, seq, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return krpc.Return{V: v, K: &pubArr, Sig: &sig, Seq: &seq}, pubArr
+}
+
+func TestServerGetAcceptsValidMutableItem(t *testing.T) {
+	v := "hello world"
+	r, pub := signedMutableReturn(t, nil, 1, v)
+	target := krpc.ID(MutableTarget(pub, nil))
+
+	s := &Server{
+		Lookup:  &fakeLookup{nodes: []TokenizedNode{{Node: node(1)}}},
+		Querier: &fakeQuerier{returns: map[krpc.ID]krpc.Return{node(1).ID: r}},
+	}
+	item, found, err := s.Get(context.Background(), target)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !found {
+		t.Fatal("Get didn't find a value a node legitimately returned")
+	}
+	if item.V != v {
+		t.Fatalf("item.V = %v, want %v", item.V, v)
+	}
+}
+
+func TestServerGetRejectsTamperedMutableItem(t *testing.T) {
+	v := "hello world"
+	r, pub := signedMutableReturn(t, nil, 1, v)
+	target := krpc.ID(MutableTarget(pub, nil))
+
+	// The node returns a different value than the one it signed; the
+	// signature no longer verifies against it.
+	r.V = "tampered"
+
+	s := &Server{
+		Lookup:  &fakeLookup{nodes: []TokenizedNode{{Node: node(1)}}},
+		Querier: &fakeQuerier{returns: map[krpc.ID]krpc.Return{node(1).ID: r}},
+	}
+	_, found, err := s.Get(context.Background(), target)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if found {
+		t.Fatal("Get accepted a mutable item whose signature doesn't verify")
+	}
+}
+
+func TestServerGetRejectsUnsignedMutableItem(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := priv.Public().(ed25519.PublicKey)
+	var pubArr [32]byte
+	copy(pubArr[:], pub)
+	target := krpc.ID(MutableTarget(pubArr, nil))
+
+	// A node claims a value under this K but supplies no signature at all.
+	r := krpc.Return{V: "hello world", K: &pubArr}
+
+	s := &Server{
+		Lookup:  &fakeLookup{nodes: []TokenizedNode{{Node: node(1)}}},
+		Querier: &fakeQuerier{returns: map[krpc.ID]krpc.Return{node(1).ID: r}},
+	}
+	_, found, err := s.Get(context.Background(), target)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if found {
+		t.Fatal("Get accepted a mutable item with no signature")
+	}
+}
+
+func TestServerGetRejectsImmutableHashMismatch(t *testing.T) {
+	var target krpc.ID
+	target[0] = 0xff // doesn't match sha1(bencode(v)) for any v below
+
+	r := krpc.Return{V: "hello world"}
+
+	s := &Server{
+		Lookup:  &fakeLookup{nodes: []TokenizedNode{{Node: node(1)}}},
+		Querier: &fakeQuerier{returns: map[krpc.ID]krpc.Return{node(1).ID: r}},
+	}
+	_, found, err := s.Get(context.Background(), target)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if found {
+		t.Fatal("Get accepted an immutable item whose hash doesn't match the requested target")
+	}
+}
+
+func TestServerGetAcceptsValidImmutableItem(t *testing.T) {
+	v := "hello world"
+	target, err := ImmutableTarget(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := krpc.Return{V: v}
+	s := &Server{
+		Lookup:  &fakeLookup{nodes: []TokenizedNode{{Node: node(1)}}},
+		Querier: &fakeQuerier{returns: map[krpc.ID]krpc.Return{node(1).ID: r}},
+	}
+	item, found, err := s.Get(context.Background(), krpc.ID(target))
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !found {
+		t.Fatal("Get didn't find a legitimately addressed immutable value")
+	}
+	if item.V != v {
+		t.Fatalf("item.V = %v, want %v", item.V, v)
+	}
+}
+
+func TestServerPut(t *testing.T) {
+	s := &Server{
+		Lookup:  &fakeLookup{nodes: []TokenizedNode{{Node: node(1)}, {Node: node(2)}}},
+		Querier: &fakeQuerier{},
+	}
+	stored, err := s.Put(context.Background(), Item{V: "hello world"}, nil)
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if stored != 2 {
+		t.Fatalf("stored = %d, want 2", stored)
+	}
+}
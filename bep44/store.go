@@ -0,0 +1,88 @@
+package bep44
+
+import (
+	"sync"
+
+	"github.com/anacrolix/torrent/bencode"
+)
+
+// Store persists BEP 44 items, keyed by their target. Implementations need
+// only provide storage; sequence enforcement is done by HandlePut, and CAS
+// enforcement by HandleCAS, so that every Store behaves the same way.
+type Store interface {
+	Get(target [20]byte) (Item, bool)
+	Put(target [20]byte, item Item) error
+}
+
+// HandlePut validates an incoming put against store's current contents and,
+// if it's valid, writes it. It returns one of the typed errors in this
+// package on failure, so callers can respond with the matching KRPC error
+// code instead of a generic one.
+func HandlePut(store Store, target [20]byte, item Item) error {
+	bv, err := bencode.Marshal(item.V)
+	if err != nil {
+		return err
+	}
+	if len(bv) > MaxValueSize {
+		return ErrValueTooBig
+	}
+	if len(item.Salt) > MaxSaltSize {
+		return ErrSaltTooBig
+	}
+	existing, ok := store.Get(target)
+	if item.Mutable() {
+		if item.Sig == nil {
+			return ErrInvalidSignature
+		}
+		var seq int64
+		if item.Seq != nil {
+			seq = *item.Seq
+		}
+		if err := Verify(*item.K, item.Salt, seq, item.V, *item.Sig); err != nil {
+			return err
+		}
+		if ok && existing.Seq != nil && item.Seq != nil && *item.Seq < *existing.Seq {
+			return ErrSeqRegression
+		}
+	}
+	return store.Put(target, item)
+}
+
+// HandleCAS is like HandlePut, but additionally enforces that cas (when
+// non-nil) matches the seq of the item currently stored at target.
+func HandleCAS(store Store, target [20]byte, item Item, cas *int64) error {
+	if cas != nil {
+		existing, ok := store.Get(target)
+		if ok && (existing.Seq == nil || *existing.Seq != *cas) {
+			return ErrCASMismatch
+		}
+	}
+	return HandlePut(store, target, item)
+}
+
+// memStore is a simple in-process Store backed by a map, suitable for
+// embedding in a DHT server or for tests.
+type memStore struct {
+	mu    sync.Mutex
+	items map[[20]byte]Item
+}
+
+// NewMemStore returns a Store that keeps items in memory for the lifetime of
+// the process.
+func NewMemStore() Store {
+	return &memStore{items: make(map[[20]byte]Item)}
+}
+
+func (s *memStore) Get(target [20]byte) (Item, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[target]
+	return item, ok
+}
+
+func (s *memStore) Put(target [20]byte, item Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[target] = item
+	return nil
+}
@@ -0,0 +1,99 @@
+package indexer
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/yarikk/dht/krpc"
+)
+
+// coverageDepth bounds how many bits of the 160-bit ID space the coverage
+// trie actually tracks. A full 160-level trie would allocate without bound
+// over a long crawl (one new node per previously-unseen ID); capping the
+// depth bounds the trie to at most 2^(coverageDepth+1)-1 nodes, at the cost
+// of only discriminating coverage down to a 2^-coverageDepth fraction of the
+// keyspace instead of to individual IDs. Bits past this depth are chosen
+// uniformly at random rather than tracked.
+const coverageDepth = 20
+
+// coverage is a prefix-tree over the first coverageDepth bits of the ID
+// space, tracking how many times each region has been targeted by a query.
+// The crawler uses it to pick new targets in under-covered regions instead
+// of clustering around nodes it already knows well.
+type coverage struct {
+	mu   sync.Mutex
+	root coverageNode
+}
+
+type coverageNode struct {
+	count    int
+	children [2]*coverageNode
+}
+
+func newCoverage() *coverage {
+	return &coverage{}
+}
+
+func idBit(id krpc.ID, i int) int {
+	return int(id[i/8]>>(7-uint(i%8))) & 1
+}
+
+// Record marks id as having been queried, incrementing every prefix node on
+// the path down to it, down to coverageDepth.
+func (c *coverage) Record(id krpc.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := &c.root
+	n.count++
+	for i := 0; i < coverageDepth; i++ {
+		bit := idBit(id, i)
+		if n.children[bit] == nil {
+			n.children[bit] = &coverageNode{}
+		}
+		n = n.children[bit]
+		n.count++
+	}
+}
+
+// NextTarget picks a pseudo-random ID, descending the trie for its first
+// coverageDepth bits and preferring the less-queried side at each level so
+// the choice is biased away from well-covered regions. The remaining bits,
+// and any level the trie hasn't recorded yet, are chosen uniformly at
+// random.
+func (c *coverage) NextTarget(r *rand.Rand) (id krpc.ID) {
+	c.mu.Lock()
+	n := &c.root
+	for i := 0; i < coverageDepth; i++ {
+		bit := r.Intn(2)
+		if n != nil {
+			left, right := childCount(n.children[0]), childCount(n.children[1])
+			switch {
+			case left < right:
+				bit = 0
+			case right < left:
+				bit = 1
+			}
+		}
+		if bit == 1 {
+			id[i/8] |= 1 << (7 - uint(i%8))
+		}
+		if n != nil {
+			n = n.children[bit]
+		}
+	}
+	c.mu.Unlock()
+
+	for i := coverageDepth; i < 160; i++ {
+		if r.Intn(2) == 1 {
+			id[i/8] |= 1 << (7 - uint(i%8))
+		}
+	}
+	return id
+}
+
+func childCount(n *coverageNode) int {
+	if n == nil {
+		return 0
+	}
+	return n.count
+}
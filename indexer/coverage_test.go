@@ -0,0 +1,29 @@
+package indexer
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/yarikk/dht/krpc"
+)
+
+func TestCoverageNextTargetPrefersUnderRecordedRegion(t *testing.T) {
+	c := newCoverage()
+	r := rand.New(rand.NewSource(1))
+
+	var hi krpc.ID
+	hi[0] = 0x80 // first bit 1
+
+	// Record many targets on the "hi" side, none on "lo": NextTarget should
+	// now be biased towards the under-recorded "lo" side at the top level.
+	for i := 0; i < 50; i++ {
+		c.Record(hi)
+	}
+
+	for i := 0; i < 20; i++ {
+		target := c.NextTarget(r)
+		if idBit(target, 0) != 0 {
+			t.Fatalf("NextTarget picked the over-recorded side at bit 0: %x", target)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package indexer
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/yarikk/dht/krpc"
+)
+
+// dedupFilter is a rolling Bloom filter used to skip infohashes the crawler
+// has already reported. It's rotated periodically: lookups check both the
+// current and previous generation, but writes only ever land in current, so
+// memory use is bounded instead of growing for the life of a long crawl.
+type dedupFilter struct {
+	mu                sync.Mutex
+	bits              uint
+	hashes            int
+	current, previous []byte
+}
+
+func newDedupFilter(bits uint, hashes int) *dedupFilter {
+	return &dedupFilter{
+		bits:    bits,
+		hashes:  hashes,
+		current: make([]byte, (bits+7)/8),
+	}
+}
+
+func (f *dedupFilter) indices(ih krpc.ID) []uint {
+	idx := make([]uint, f.hashes)
+	for i := range idx {
+		h := fnv.New64a()
+		h.Write(ih[:])
+		h.Write([]byte{byte(i)})
+		idx[i] = uint(h.Sum64() % uint64(f.bits))
+	}
+	return idx
+}
+
+// SeenOrRecord reports whether ih looks like a duplicate, and in either case
+// records it as present in the current generation.
+func (f *dedupFilter) SeenOrRecord(ih krpc.ID) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	idx := f.indices(ih)
+	seen := testAll(f.current, idx) || testAll(f.previous, idx)
+	for _, i := range idx {
+		f.current[i/8] |= 1 << (i % 8)
+	}
+	return seen
+}
+
+func testAll(bitset []byte, idx []uint) bool {
+	if bitset == nil {
+		return false
+	}
+	for _, i := range idx {
+		if bitset[i/8]&(1<<(i%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Rotate starts a fresh generation, demoting the current one to previous.
+func (f *dedupFilter) Rotate() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.previous = f.current
+	f.current = make([]byte, (f.bits+7)/8)
+}
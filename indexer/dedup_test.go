@@ -0,0 +1,36 @@
+package indexer
+
+import (
+	"testing"
+
+	"github.com/yarikk/dht/krpc"
+)
+
+func TestDedupFilterSeenOrRecord(t *testing.T) {
+	f := newDedupFilter(1<<10, 4)
+	var a, b krpc.ID
+	a[0], b[0] = 1, 2
+
+	if f.SeenOrRecord(a) {
+		t.Fatal("first sighting of a reported as already seen")
+	}
+	if !f.SeenOrRecord(a) {
+		t.Fatal("second sighting of a not reported as already seen")
+	}
+	if f.SeenOrRecord(b) {
+		t.Fatal("first sighting of b reported as already seen")
+	}
+}
+
+func TestDedupFilterRotateForgetsOldestGeneration(t *testing.T) {
+	f := newDedupFilter(1<<10, 4)
+	var a krpc.ID
+	a[0] = 1
+
+	f.SeenOrRecord(a)
+	f.Rotate() // a moves from current to previous; current starts empty
+	f.Rotate() // a's only generation is now discarded entirely
+	if f.SeenOrRecord(a) {
+		t.Fatal("a should no longer be recognised two rotations after it was recorded")
+	}
+}
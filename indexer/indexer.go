@@ -0,0 +1,260 @@
+// Package indexer implements a BEP 51 (http://bittorrent.org/beps/bep_0051.html)
+// infohash indexing crawler: it walks the keyspace with sample_infohashes
+// queries, discovering infohashes and new nodes to query as it goes, and
+// emits what it finds on a channel.
+package indexer
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yarikk/dht/krpc"
+)
+
+// Querier issues the two query types the crawler needs against a node: a
+// coverage-biased sample_infohashes to harvest infohashes, and a FindNode to
+// discover more nodes towards the next under-covered target.
+type Querier interface {
+	FindNode(ctx context.Context, node krpc.NodeInfo, target krpc.ID) (krpc.Return, error)
+	SampleInfohashes(ctx context.Context, node krpc.NodeInfo, target krpc.ID) (krpc.Return, error)
+}
+
+// Metrics are the crawler's running counters. Safe for concurrent reads
+// while the crawler is running.
+type Metrics struct {
+	Queries          int64
+	Responses        int64
+	UniqueInfohashes int64
+}
+
+// QueriesPerSecond computes a rate from Queries and the given elapsed time.
+func (m *Metrics) QueriesPerSecond(elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&m.Queries)) / elapsed.Seconds()
+}
+
+// Config configures an Indexer.
+type Config struct {
+	// Concurrency is the number of nodes queried in parallel.
+	Concurrency int
+	// DedupBits/DedupHashes size the rolling Bloom filter used to recognise
+	// infohashes already reported this generation.
+	DedupBits   uint
+	DedupHashes int
+	// DedupRotateInterval is how often the dedup filter starts a fresh
+	// generation, bounding its memory use over a long-running crawl. See
+	// dedupFilter.Rotate.
+	DedupRotateInterval time.Duration
+}
+
+// DefaultConfig returns sensible defaults for Config.
+func DefaultConfig() Config {
+	return Config{
+		Concurrency:         8,
+		DedupBits:           1 << 20,
+		DedupHashes:         4,
+		DedupRotateInterval: time.Hour,
+	}
+}
+
+// NodeRate is a snapshot of how a single node has been responding.
+type NodeRate struct {
+	Queries, Responses int64
+}
+
+// Indexer crawls the DHT via sample_infohashes, discovering infohashes
+// approximately uniformly across the keyspace rather than clustering around
+// the nodes closest to the local ID.
+type Indexer struct {
+	q     Querier
+	cfg   Config
+	cov   *coverage
+	dedup *dedupFilter
+
+	metrics Metrics
+
+	mu      sync.Mutex
+	backoff map[krpc.ID]time.Time
+	rates   map[krpc.ID]*NodeRate
+}
+
+// New creates an Indexer that queries nodes via q.
+func New(q Querier, cfg Config) *Indexer {
+	return &Indexer{
+		q:       q,
+		cfg:     cfg,
+		cov:     newCoverage(),
+		dedup:   newDedupFilter(cfg.DedupBits, cfg.DedupHashes),
+		backoff: make(map[krpc.ID]time.Time),
+		rates:   make(map[krpc.ID]*NodeRate),
+	}
+}
+
+// Metrics returns a snapshot of the crawler's counters.
+func (ix *Indexer) Metrics() Metrics {
+	return Metrics{
+		Queries:          atomic.LoadInt64(&ix.metrics.Queries),
+		Responses:        atomic.LoadInt64(&ix.metrics.Responses),
+		UniqueInfohashes: atomic.LoadInt64(&ix.metrics.UniqueInfohashes),
+	}
+}
+
+// NodeRate returns the query/response counts seen for a specific node.
+func (ix *Indexer) NodeRate(node krpc.ID) NodeRate {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if r, ok := ix.rates[node]; ok {
+		return *r
+	}
+	return NodeRate{}
+}
+
+// Run crawls starting from seeds until ctx is cancelled, sending newly
+// discovered infohashes on the returned channel. Run returns immediately; the
+// channel is closed once ctx is cancelled and every worker has stopped.
+func (ix *Indexer) Run(ctx context.Context, seeds []krpc.NodeInfo) <-chan krpc.ID {
+	out := make(chan krpc.ID)
+	go func() {
+		defer close(out)
+
+		work := make(chan krpc.NodeInfo, ix.cfg.Concurrency*4)
+		var wg sync.WaitGroup
+		for i := 0; i < ix.cfg.Concurrency; i++ {
+			wg.Add(1)
+			go func(seed int64) {
+				defer wg.Done()
+				ix.worker(ctx, rand.New(rand.NewSource(seed)), work, out)
+			}(time.Now().UnixNano() + int64(i))
+		}
+
+		for _, n := range seeds {
+			select {
+			case work <- n:
+			case <-ctx.Done():
+			}
+		}
+		// work is never closed: it has multiple senders (this loop and
+		// every worker's FindNode discoveries), so shutdown is driven by
+		// ctx alone. New targets are chosen from the coverage trie and
+		// queried against nodes discovered this way, not fabricated as
+		// standalone nodes.
+		go ix.rotateDedupPeriodically(ctx)
+
+		wg.Wait()
+	}()
+	return out
+}
+
+func (ix *Indexer) worker(ctx context.Context, rnd *rand.Rand, work chan krpc.NodeInfo, out chan<- krpc.ID) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case node := <-work:
+			ix.query(ctx, node, rnd, work, out)
+		}
+	}
+}
+
+func (ix *Indexer) query(ctx context.Context, node krpc.NodeInfo, rnd *rand.Rand, work chan krpc.NodeInfo, out chan<- krpc.ID) {
+	if until, ok := ix.nextAllowed(node.ID); ok && time.Now().Before(until) {
+		return
+	}
+	target := ix.cov.NextTarget(rnd)
+	ix.cov.Record(target)
+
+	atomic.AddInt64(&ix.metrics.Queries, 1)
+	ix.recordRate(node.ID, true, false)
+	r, err := ix.q.SampleInfohashes(ctx, node, target)
+	if err != nil {
+		return
+	}
+	atomic.AddInt64(&ix.metrics.Responses, 1)
+	ix.recordRate(node.ID, false, true)
+
+	if r.Interval != nil {
+		ix.setBackoff(node.ID, time.Duration(*r.Interval)*time.Second)
+	}
+	var samples krpc.CompactInfohashes
+	if r.Samples != nil {
+		samples = *r.Samples
+	}
+	for _, ih := range samples {
+		if ix.dedup.SeenOrRecord(ih) {
+			continue
+		}
+		atomic.AddInt64(&ix.metrics.UniqueInfohashes, 1)
+		select {
+		case out <- ih:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	// Discover more nodes towards the next under-covered target, so the
+	// crawl keeps spreading instead of only ever revisiting seeds.
+	fnTarget := ix.cov.NextTarget(rnd)
+	ix.cov.Record(fnTarget)
+	if fn, err := ix.q.FindNode(ctx, node, fnTarget); err == nil {
+		fn.ForAllNodes(func(n krpc.NodeInfo) {
+			select {
+			case work <- n:
+			case <-ctx.Done():
+			default:
+			}
+		})
+	}
+}
+
+// rotateDedupPeriodically rotates the dedup filter's generation on
+// ix.cfg.DedupRotateInterval until ctx is done, bounding its memory use
+// instead of letting it grow for the life of a long-running crawl.
+func (ix *Indexer) rotateDedupPeriodically(ctx context.Context) {
+	if ix.cfg.DedupRotateInterval <= 0 {
+		return
+	}
+	t := time.NewTicker(ix.cfg.DedupRotateInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			ix.dedup.Rotate()
+		}
+	}
+}
+
+func (ix *Indexer) nextAllowed(node krpc.ID) (time.Time, bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	t, ok := ix.backoff[node]
+	return t, ok
+}
+
+func (ix *Indexer) setBackoff(node krpc.ID, d time.Duration) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.backoff[node] = time.Now().Add(d)
+}
+
+func (ix *Indexer) recordRate(node krpc.ID, query, response bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	r, ok := ix.rates[node]
+	if !ok {
+		r = &NodeRate{}
+		ix.rates[node] = r
+	}
+	if query {
+		r.Queries++
+	}
+	if response {
+		r.Responses++
+	}
+}
@@ -0,0 +1,48 @@
+package indexer
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/yarikk/dht/krpc"
+)
+
+type fakeIndexerQuerier struct{}
+
+func (fakeIndexerQuerier) FindNode(ctx context.Context, node krpc.NodeInfo, target krpc.ID) (krpc.Return, error) {
+	return krpc.Return{}, nil
+}
+
+func (fakeIndexerQuerier) SampleInfohashes(ctx context.Context, node krpc.NodeInfo, target krpc.ID) (krpc.Return, error) {
+	return krpc.Return{}, nil
+}
+
+// TestQueryRecordsChosenTargetNotNodeID guards against recording the
+// queried peer's own ID in the coverage trie instead of the sampled
+// target. Every call below queries the same node, so if query() recorded
+// node.ID, every Record would walk the identical trie path (node.ID's
+// bits are constant, so only the branch matching its first bit would ever
+// gain a count). Recording the chosen target instead means the pseudo-random
+// targets spread across both branches.
+func TestQueryRecordsChosenTargetNotNodeID(t *testing.T) {
+	ix := New(fakeIndexerQuerier{}, DefaultConfig())
+
+	var fixedNode krpc.NodeInfo
+	for i := range fixedNode.ID {
+		fixedNode.ID[i] = 0xff
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	work := make(chan krpc.NodeInfo, 64)
+	out := make(chan krpc.ID, 64)
+	ctx := context.Background()
+
+	for i := 0; i < 40; i++ {
+		ix.query(ctx, fixedNode, rnd, work, out)
+	}
+
+	if ix.cov.root.children[0] == nil || ix.cov.root.children[0].count == 0 {
+		t.Fatal("coverage trie's bit-0 branch was never recorded; query() is likely recording the queried node's own ID (always bit 1 here) instead of the sampled target")
+	}
+}
@@ -0,0 +1,108 @@
+package krpc
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"math/bits"
+
+	"github.com/anacrolix/torrent/bencode"
+)
+
+// ScrapeBloomFilter is the 256-byte (2048-bit) Bloom filter BEP 33 uses to
+// estimate swarm population. BFpe ("peers") counts every peer that has ever
+// announced for the infohash; BFsd ("seeds") counts only the seeds.
+type ScrapeBloomFilter [256]byte
+
+const scrapeBloomFilterBits = 256 * 8 // bits in a ScrapeBloomFilter
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// bloomFilterIndices returns the pair of bit indices BEP 33 sets for
+// infoHash: the CRC32-C of the infohash, and the CRC32-C of its SHA-1,
+// both reduced mod the filter's bit width.
+func bloomFilterIndices(infoHash [20]byte) (i1, i2 uint32) {
+	sum1 := crc32.Checksum(infoHash[:], crc32c)
+	h2 := sha1.Sum(infoHash[:])
+	sum2 := crc32.Checksum(h2[:], crc32c)
+	return sum1 % scrapeBloomFilterBits, sum2 % scrapeBloomFilterBits
+}
+
+// Set marks infoHash as present in the filter.
+func (bf *ScrapeBloomFilter) Set(infoHash [20]byte) {
+	i1, i2 := bloomFilterIndices(infoHash)
+	bf.setBit(i1)
+	bf.setBit(i2)
+}
+
+// Test reports whether infoHash may be present in the filter. As with any
+// Bloom filter this can false-positive, but never false-negatives.
+func (bf ScrapeBloomFilter) Test(infoHash [20]byte) bool {
+	i1, i2 := bloomFilterIndices(infoHash)
+	return bf.testBit(i1) && bf.testBit(i2)
+}
+
+func (bf *ScrapeBloomFilter) setBit(i uint32) {
+	bf[i/8] |= 1 << (i % 8)
+}
+
+func (bf ScrapeBloomFilter) testBit(i uint32) bool {
+	return bf[i/8]&(1<<(i%8)) != 0
+}
+
+// SetBits returns the number of bits set in the filter.
+func (bf ScrapeBloomFilter) SetBits() int {
+	n := 0
+	for _, b := range bf {
+		n += bits.OnesCount8(b)
+	}
+	return n
+}
+
+// Merge ORs other into bf in place, as is done when combining the filters
+// returned by multiple nodes for the same infohash.
+func (bf *ScrapeBloomFilter) Merge(other ScrapeBloomFilter) {
+	for i := range bf {
+		bf[i] |= other[i]
+	}
+}
+
+// EstimatedSize estimates the number of distinct items represented in the
+// filter, using the standard Bloom filter cardinality estimator with the
+// BEP 33 parameters (m=2048 bits, k=2 hash functions):
+//
+//	size = -(m/k) * ln(1 - set_bits/m)
+//
+// For a fully-saturated filter (every bit set) the formula diverges, so
+// set_bits is clamped to m-1, capping the estimate at the filter's maximum
+// representable value.
+func (bf ScrapeBloomFilter) EstimatedSize() int64 {
+	c := bf.SetBits()
+	if c == 0 {
+		return 0
+	}
+	if c >= scrapeBloomFilterBits-1 {
+		c = scrapeBloomFilterBits - 1
+	}
+	const m = float64(scrapeBloomFilterBits)
+	const k = 2
+	size := -(m / k) * math.Log1p(-float64(c)/m)
+	return int64(size + 0.5)
+}
+
+func (bf ScrapeBloomFilter) MarshalBencode() ([]byte, error) {
+	return bencode.Marshal(string(bf[:]))
+}
+
+func (bf *ScrapeBloomFilter) UnmarshalBencode(b []byte) error {
+	var s string
+	if err := bencode.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if len(s) != len(bf) {
+		return fmt.Errorf("expected %d bytes, got %d", len(bf), len(s))
+	}
+	copy(bf[:], s)
+	return nil
+}
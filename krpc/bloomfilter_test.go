@@ -0,0 +1,55 @@
+package krpc
+
+import "testing"
+
+func TestScrapeBloomFilterEmpty(t *testing.T) {
+	var bf ScrapeBloomFilter
+	if n := bf.EstimatedSize(); n != 0 {
+		t.Fatalf("empty filter estimated %d, want 0", n)
+	}
+}
+
+func TestScrapeBloomFilterSetTest(t *testing.T) {
+	var bf ScrapeBloomFilter
+	var ih [20]byte
+	ih[0] = 1
+	if bf.Test(ih) {
+		t.Fatal("unset infohash reported present")
+	}
+	bf.Set(ih)
+	if !bf.Test(ih) {
+		t.Fatal("set infohash reported absent")
+	}
+}
+
+// TestScrapeBloomFilterEstimatedSizeKnownVector checks the estimator against
+// a filter with a known number of set bits, rather than against Set (which
+// would only prove Set and EstimatedSize agree with each other). With
+// exactly half of the filter's 2048 bits set, the BEP 33 formula
+// -(m/k)*ln(1-c/m) with m=2048, k=2, c=1024 works out to ~709.8.
+func TestScrapeBloomFilterEstimatedSizeKnownVector(t *testing.T) {
+	var bf ScrapeBloomFilter
+	for i := 0; i < len(bf); i++ {
+		bf[i] = 0b01010101 // 4 of 8 bits per byte set, evenly spread
+	}
+	if got, want := bf.SetBits(), 1024; got != want {
+		t.Fatalf("SetBits() = %d, want %d", got, want)
+	}
+	got := bf.EstimatedSize()
+	const want = 710
+	if got != want {
+		t.Fatalf("EstimatedSize() = %d, want %d", got, want)
+	}
+}
+
+func TestScrapeBloomFilterMerge(t *testing.T) {
+	var a, b ScrapeBloomFilter
+	var ih1, ih2 [20]byte
+	ih1[0], ih2[0] = 1, 2
+	a.Set(ih1)
+	b.Set(ih2)
+	a.Merge(b)
+	if !a.Test(ih1) || !a.Test(ih2) {
+		t.Fatal("merged filter should test positive for both infohashes")
+	}
+}
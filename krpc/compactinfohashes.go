@@ -0,0 +1,36 @@
+package krpc
+
+import (
+	"fmt"
+
+	"github.com/anacrolix/torrent/bencode"
+)
+
+// CompactInfohashes is a concatenation of 20-byte infohashes, as carried in
+// the "samples" field of a sample_infohashes response (BEP 51).
+type CompactInfohashes []ID
+
+func (cis CompactInfohashes) MarshalBencode() ([]byte, error) {
+	b := make([]byte, 0, len(cis)*20)
+	for _, ih := range cis {
+		b = append(b, ih[:]...)
+	}
+	return bencode.Marshal(string(b))
+}
+
+func (cis *CompactInfohashes) UnmarshalBencode(b []byte) error {
+	var s string
+	if err := bencode.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if len(s)%20 != 0 {
+		return fmt.Errorf("length %d is not a multiple of 20", len(s))
+	}
+	*cis = make(CompactInfohashes, 0, len(s)/20)
+	for i := 0; i < len(s); i += 20 {
+		var id ID
+		copy(id[:], s[i:i+20])
+		*cis = append(*cis, id)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package krpc
+
+import (
+	"fmt"
+
+	"github.com/anacrolix/torrent/bencode"
+)
+
+// ErrorCode is the numeric code carried in the "e" field of a KRPC error
+// message.
+type ErrorCode int
+
+// The standard KRPC error codes. 201-204 are from BEP 5, 205-207 and
+// 301-302 are from BEP 44.
+const (
+	ErrorCodeGenericError  ErrorCode = 201
+	ErrorCodeServerError   ErrorCode = 202
+	ErrorCodeProtocolError ErrorCode = 203
+	ErrorCodeMethodUnknown ErrorCode = 204
+
+	// BEP 44
+	ErrorCodeValueTooBig        ErrorCode = 205
+	ErrorCodeInvalidSignature   ErrorCode = 206
+	ErrorCodeSaltTooBig         ErrorCode = 207
+	ErrorCodeCASMismatch        ErrorCode = 301
+	ErrorCodeSeqLessThanCurrent ErrorCode = 302
+)
+
+// errorCodeText is the registry of known error codes, so callers can look up
+// a human-readable description (or test membership) without hardcoding or
+// parsing the message string.
+var errorCodeText = map[ErrorCode]string{
+	ErrorCodeGenericError:       "Generic Error",
+	ErrorCodeServerError:        "Server Error",
+	ErrorCodeProtocolError:      "Protocol Error",
+	ErrorCodeMethodUnknown:      "Method Unknown",
+	ErrorCodeValueTooBig:        "Value Too Big",
+	ErrorCodeInvalidSignature:   "Invalid Signature",
+	ErrorCodeSaltTooBig:         "Salt Too Big",
+	ErrorCodeCASMismatch:        "CAS Mismatch",
+	ErrorCodeSeqLessThanCurrent: "Sequence Number Less Than Current",
+}
+
+// String returns the registered description for c, or a generic placeholder
+// if c isn't one of the known codes.
+func (c ErrorCode) String() string {
+	if s, ok := errorCodeText[c]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown Error %d", int(c))
+}
+
+// Known reports whether c is one of the error codes this package recognises.
+func (c ErrorCode) Known() bool {
+	_, ok := errorCodeText[c]
+	return ok
+}
+
+// Error is the value of the "e" key in an error message: a two-element
+// bencoded list of [code, message]. It implements the error interface, so
+// it can be returned and matched with errors.Is/errors.As like any other
+// Go error.
+type Error struct {
+	Code ErrorCode
+	Msg  string
+}
+
+var _ error = Error{}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("KRPC error %d: %s: %s", e.Code, e.Code, e.Msg)
+}
+
+// Is lets errors.Is(err, krpc.Error{Code: krpc.ErrorCodeProtocolError}) match
+// any Error with the same code, ignoring Msg.
+func (e Error) Is(target error) bool {
+	other, ok := target.(Error)
+	if !ok {
+		return false
+	}
+	return e.Code == other.Code
+}
+
+// Temporary reports whether the error reflects a transient condition on the
+// remote node, as opposed to a malformed query that will never succeed.
+func (e Error) Temporary() bool {
+	return e.Code == ErrorCodeServerError
+}
+
+func (e Error) MarshalBencode() ([]byte, error) {
+	return bencode.Marshal([]interface{}{e.Code, e.Msg})
+}
+
+func (e *Error) UnmarshalBencode(b []byte) error {
+	var list []interface{}
+	if err := bencode.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	if len(list) != 2 {
+		return fmt.Errorf("expected 2 elements, got %d", len(list))
+	}
+	code, ok := list[0].(int64)
+	if !ok {
+		return fmt.Errorf("expected int error code, got %T", list[0])
+	}
+	msg, ok := list[1].(string)
+	if !ok {
+		return fmt.Errorf("expected string error message, got %T", list[1])
+	}
+	e.Code = ErrorCode(code)
+	e.Msg = msg
+	return nil
+}
+
+// NewError constructs an Error with the given code and message.
+func NewError(code ErrorCode, msg string) *Error {
+	return &Error{Code: code, Msg: msg}
+}
+
+// NewGenericError constructs a 201 Generic Error.
+func NewGenericError(msg string) *Error {
+	return NewError(ErrorCodeGenericError, msg)
+}
+
+// NewServerError constructs a 202 Server Error.
+func NewServerError(msg string) *Error {
+	return NewError(ErrorCodeServerError, msg)
+}
+
+// NewProtocolError constructs a 203 Protocol Error, for malformed packets,
+// invalid arguments, or bad tokens.
+func NewProtocolError(msg string) *Error {
+	return NewError(ErrorCodeProtocolError, msg)
+}
+
+// NewMethodUnknownError constructs a 204 Method Unknown error for the given
+// query method.
+func NewMethodUnknownError(method string) *Error {
+	return NewError(ErrorCodeMethodUnknown, fmt.Sprintf("Method Unknown: %q", method))
+}
@@ -36,7 +36,12 @@ type MsgArgs struct {
 	Scrape      int    `bencode:"scrape,omitempty"`       // BEP 33
 
 	// BEP 44
-	V interface{} `bencode:"v,omitempty"`
+	V    interface{} `bencode:"v,omitempty"`    // Value to store, for a put
+	K    *[32]byte   `bencode:"k,omitempty"`    // Ed25519 public key, for a mutable put
+	Sig  *[64]byte   `bencode:"sig,omitempty"`  // Ed25519 signature, for a mutable put
+	Seq  *int64      `bencode:"seq,omitempty"`  // Sequence number, for a mutable put or get
+	Cas  *int64      `bencode:"cas,omitempty"`  // Expected current seq, for a compare-and-swap put
+	Salt []byte      `bencode:"salt,omitempty"` // Salt, for a mutable put or get
 }
 
 type Want string
@@ -75,7 +80,10 @@ type Return struct {
 	Bep51Return
 
 	// BEP 44 get
-	V interface{} `bencode:"v,omitempty"`
+	V   interface{} `bencode:"v,omitempty"`
+	K   *[32]byte   `bencode:"k,omitempty"`   // Ed25519 public key, for a mutable get
+	Sig *[64]byte   `bencode:"sig,omitempty"` // Ed25519 signature, for a mutable get
+	Seq *int64      `bencode:"seq,omitempty"` // Sequence number of the returned value, for a mutable get
 }
 
 func (r Return) ForAllNodes(f func(NodeInfo)) {
@@ -106,9 +114,14 @@ func (m Msg) SenderID() *ID {
 	return nil
 }
 
-func (m Msg) Error() *Error {
-	if m.Y != "e" {
+// Error returns the message's error as a value satisfying the error
+// interface, so it can flow through errors.Is/errors.As, or nil if the
+// message isn't of type ERROR. Note this dereferences the Msg.E field rather
+// than returning *Error directly, so a nil Msg.E can't leak out as a non-nil
+// error interface value.
+func (m Msg) Error() error {
+	if m.Y != "e" || m.E == nil {
 		return nil
 	}
-	return m.E
+	return *m.E
 }
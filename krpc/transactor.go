@@ -0,0 +1,195 @@
+package krpc
+
+import (
+	"context"
+	crand "crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TxnIDAllocator mints transaction IDs for outgoing queries. The default
+// implementations are MonotonicTxnIDAllocator and RandomTxnIDAllocator;
+// callers embedding this package in a crawler can supply their own, for
+// example to encode a query-type hint in the 2 bytes the way several
+// mainline implementations do.
+type TxnIDAllocator interface {
+	Next() string
+}
+
+// MonotonicTxnIDAllocator mints sequential 2-byte transaction IDs, wrapping
+// at 2^16. It's cheap, but predictable, so it is unsuitable where an
+// adversary guessing outstanding transaction IDs is a concern.
+type MonotonicTxnIDAllocator struct {
+	mu   sync.Mutex
+	next uint16
+}
+
+func (a *MonotonicTxnIDAllocator) Next() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	t := a.next
+	a.next++
+	return string([]byte{byte(t >> 8), byte(t)})
+}
+
+// RandomTxnIDAllocator mints 2 cryptographically random bytes per
+// transaction ID.
+type RandomTxnIDAllocator struct{}
+
+func (RandomTxnIDAllocator) Next() string {
+	var b [2]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		// crypto/rand.Read on any supported platform doesn't fail.
+		panic(err)
+	}
+	return string(b[:])
+}
+
+// TransactorErrorCode distinguishes the ways a tracked query can fail
+// locally, as opposed to the codes a remote peer sends back in an "e"
+// message (see ErrorCode).
+type TransactorErrorCode int
+
+const (
+	TransactorErrorTimeout TransactorErrorCode = iota + 1
+	TransactorErrorCollision
+)
+
+// TransactorError is returned by a Transactor's wait function, or reported
+// through OnCollision, when a query can't be resolved normally.
+type TransactorError struct {
+	Code TransactorErrorCode
+	Addr string
+	T    string
+}
+
+func (e *TransactorError) Error() string {
+	switch e.Code {
+	case TransactorErrorTimeout:
+		return fmt.Sprintf("krpc: query %q to %s timed out", e.T, e.Addr)
+	case TransactorErrorCollision:
+		return fmt.Sprintf("krpc: transaction id %q reused for %s while still outstanding", e.T, e.Addr)
+	default:
+		return "krpc: transactor error"
+	}
+}
+
+type txnKey struct {
+	addr string
+	t    string
+}
+
+type pendingQuery struct {
+	query string // query method, kept for diagnostics
+	done  chan Msg
+}
+
+// Transactor mints transaction IDs for outgoing queries, tracks them as
+// outstanding until a matching response or error arrives or the deadline
+// passes, and matches incoming messages back to the query they answer.
+// Queries are keyed by (remote address, transaction ID), since transaction
+// IDs are only required to be unique per remote node at a time.
+type Transactor struct {
+	alloc   TxnIDAllocator
+	timeout time.Duration
+
+	// OnCollision, if set, is called when Query is asked to track a
+	// (addr, t) pair that's already outstanding, which only happens if
+	// alloc reused an ID too soon. The colliding, still-unanswered query is
+	// no longer tracked: no response reaches its waiter, which returns a
+	// timeout TransactorError (or ctx's error) once its own wait ends.
+	OnCollision func(*TransactorError)
+
+	mu      sync.Mutex
+	pending map[txnKey]*pendingQuery
+}
+
+// NewTransactor creates a Transactor that allocates IDs from alloc and waits
+// up to timeout for a response before giving up on a query.
+func NewTransactor(alloc TxnIDAllocator, timeout time.Duration) *Transactor {
+	return &Transactor{
+		alloc:   alloc,
+		timeout: timeout,
+		pending: make(map[txnKey]*pendingQuery),
+	}
+}
+
+// Query mints a transaction ID for a query of the given method to addr,
+// registers it as outstanding, and returns the ID to set as Msg.T along
+// with a function that waits for the matching response.
+func (tx *Transactor) Query(addr, query string) (t string, wait func(ctx context.Context) (Msg, error)) {
+	t = tx.alloc.Next()
+	key := txnKey{addr: addr, t: t}
+	pq := &pendingQuery{query: query, done: make(chan Msg, 1)}
+
+	tx.mu.Lock()
+	_, collision := tx.pending[key]
+	if collision {
+		delete(tx.pending, key)
+	}
+	tx.pending[key] = pq
+	tx.mu.Unlock()
+
+	// Called outside the lock: OnCollision is user-supplied and may
+	// reentrantly call back into the Transactor (e.g. to log and retry),
+	// which would deadlock if tx.mu were still held.
+	if collision && tx.OnCollision != nil {
+		tx.OnCollision(&TransactorError{Code: TransactorErrorCollision, Addr: addr, T: t})
+	}
+
+	return t, func(ctx context.Context) (Msg, error) {
+		timer := time.NewTimer(tx.timeout)
+		defer timer.Stop()
+		select {
+		case m := <-pq.done:
+			return m, nil
+		case <-timer.C:
+			tx.abandon(key, pq)
+			return Msg{}, &TransactorError{Code: TransactorErrorTimeout, Addr: addr, T: t}
+		case <-ctx.Done():
+			tx.abandon(key, pq)
+			return Msg{}, ctx.Err()
+		}
+	}
+}
+
+// abandon removes pq from the pending map, but only if it's still the entry
+// registered under key. A collision can evict pq and replace it with a new
+// query reusing the same key before pq's own timeout or ctx.Done() fires;
+// without this identity check, abandon would delete the new, legitimate
+// entry instead of the stale one it actually belongs to.
+func (tx *Transactor) abandon(key txnKey, pq *pendingQuery) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.pending[key] == pq {
+		delete(tx.pending, key)
+	}
+}
+
+// HandleResponse delivers an incoming r or e message from addr to the
+// waiter for its transaction ID, if one is still outstanding. It reports
+// whether a match was found; an unmatched response isn't necessarily an
+// error; it's normal for a late or duplicate reply to arrive after its
+// query has already timed out.
+func (tx *Transactor) HandleResponse(addr string, m Msg) bool {
+	key := txnKey{addr: addr, t: m.T}
+	tx.mu.Lock()
+	pq, ok := tx.pending[key]
+	if ok {
+		delete(tx.pending, key)
+	}
+	tx.mu.Unlock()
+	if !ok {
+		return false
+	}
+	pq.done <- m
+	return true
+}
+
+// Outstanding returns the number of queries still awaiting a response.
+func (tx *Transactor) Outstanding() int {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return len(tx.pending)
+}
@@ -0,0 +1,83 @@
+package krpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTransactorMatchesResponse(t *testing.T) {
+	tx := NewTransactor(&MonotonicTxnIDAllocator{}, time.Second)
+	t_, wait := tx.Query("node1", "ping")
+
+	if !tx.HandleResponse("node1", Msg{T: t_, Y: "r", R: &Return{}}) {
+		t.Fatal("HandleResponse didn't match the outstanding query")
+	}
+	m, err := wait(context.Background())
+	if err != nil {
+		t.Fatalf("wait returned an error for a matched response: %v", err)
+	}
+	if m.Y != "r" {
+		t.Fatalf("wait returned the wrong message: %+v", m)
+	}
+	if tx.Outstanding() != 0 {
+		t.Fatalf("Outstanding() = %d, want 0 after the query resolved", tx.Outstanding())
+	}
+}
+
+func TestTransactorUnmatchedResponse(t *testing.T) {
+	tx := NewTransactor(&MonotonicTxnIDAllocator{}, time.Second)
+	if tx.HandleResponse("node1", Msg{T: "nope", Y: "r", R: &Return{}}) {
+		t.Fatal("HandleResponse matched a transaction that was never registered")
+	}
+}
+
+func TestTransactorTimeout(t *testing.T) {
+	tx := NewTransactor(&MonotonicTxnIDAllocator{}, time.Millisecond)
+	_, wait := tx.Query("node1", "ping")
+
+	_, err := wait(context.Background())
+	var terr *TransactorError
+	if !errors.As(err, &terr) || terr.Code != TransactorErrorTimeout {
+		t.Fatalf("wait() error = %v, want a TransactorError with code TransactorErrorTimeout", err)
+	}
+	if tx.Outstanding() != 0 {
+		t.Fatalf("Outstanding() = %d, want 0 after the query timed out", tx.Outstanding())
+	}
+}
+
+func TestTransactorCollision(t *testing.T) {
+	tx := NewTransactor(&fixedTxnIDAllocator{id: "xx"}, time.Second)
+
+	var collisions []*TransactorError
+	tx.OnCollision = func(e *TransactorError) { collisions = append(collisions, e) }
+
+	ctx1, cancel1 := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel1()
+	_, wait1 := tx.Query("node1", "ping")
+	_, wait2 := tx.Query("node1", "find_node")
+
+	if len(collisions) != 1 || collisions[0].Code != TransactorErrorCollision {
+		t.Fatalf("OnCollision fired %d times with %+v, want exactly one TransactorErrorCollision", len(collisions), collisions)
+	}
+
+	// The first query's wait times out (via ctx1) well before the second
+	// query is resolved below, so its abandon() runs while "xx" is
+	// registered to the second, legitimate query. That abandon must not
+	// delete the second query's entry.
+	if _, err := wait1(ctx1); err == nil {
+		t.Fatal("evicted query's wait should not resolve")
+	}
+
+	if !tx.HandleResponse("node1", Msg{T: "xx", Y: "r", R: &Return{}}) {
+		t.Fatal("HandleResponse didn't match the surviving query; the evicted query's abandon likely deleted it")
+	}
+	if _, err := wait2(context.Background()); err != nil {
+		t.Fatalf("second query: %v", err)
+	}
+}
+
+type fixedTxnIDAllocator struct{ id string }
+
+func (a *fixedTxnIDAllocator) Next() string { return a.id }
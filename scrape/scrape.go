@@ -0,0 +1,137 @@
+// Package scrape implements BEP 33 (http://bittorrent.org/beps/bep_0033.html)
+// scrapes: estimating a torrent's swarm population from the Bloom filters
+// nodes return alongside a get_peers reply when the query sets scrape=1.
+//
+// Scraper.Scrape and Scraper.StreamScrape drive the actual network
+// operation: they merge the Bloom filters from a supplied Lookup's
+// iterative get_peers traversal as it progresses, the same way bep44.Server
+// drives a supplied Lookup for its get/put operations.
+package scrape
+
+import (
+	"context"
+
+	"github.com/yarikk/dht/krpc"
+)
+
+// Result is a swarm population estimate derived from the Bloom filters
+// collected so far for an infohash.
+type Result struct {
+	// Responses is the number of distinct nodes whose filters have been
+	// merged in.
+	Responses int
+	Seeds     int64
+	Peers     int64
+}
+
+// Leechers is the estimated number of non-seeding peers (downloaders).
+func (r Result) Leechers() int64 {
+	l := r.Peers - r.Seeds
+	if l < 0 {
+		return 0
+	}
+	return l
+}
+
+// Lookup performs the iterative get_peers traversal for infoHash with
+// scrape=1 set, sending each responding node's Return on the returned
+// channel as it arrives so a Scrape can start merging filters before the
+// full K-closest traversal converges. The channel is closed once the
+// traversal finishes or ctx is done. Scraper calls it once per Scrape or
+// StreamScrape, for the infohash it was created with.
+type Lookup interface {
+	GetPeersScrape(ctx context.Context, infoHash krpc.ID) (<-chan krpc.Return, error)
+}
+
+// Scraper accumulates Bloom filters for a single infohash as nodes respond,
+// driving lookup to perform the underlying get_peers traversal. A Scraper is
+// bound to one infohash and one traversal: create a new one for each
+// infohash scraped, rather than reusing a Scraper across infohashes, which
+// would merge filters for two different swarms into one estimate.
+type Scraper struct {
+	lookup   Lookup
+	infoHash krpc.ID
+
+	bfsd krpc.ScrapeBloomFilter // seeds
+	bfpe krpc.ScrapeBloomFilter // all peers
+	n    int
+}
+
+// NewScraper creates a Scraper that scrapes infoHash via lookup.
+func NewScraper(lookup Lookup, infoHash krpc.ID) *Scraper {
+	return &Scraper{lookup: lookup, infoHash: infoHash}
+}
+
+// add merges a single node's response into the running estimate. It's a
+// no-op if the response carried neither filter, which happens for nodes
+// that don't support BEP 33.
+func (s *Scraper) add(r krpc.Return) {
+	if r.BFsd == nil && r.BFpe == nil {
+		return
+	}
+	if r.BFsd != nil {
+		s.bfsd.Merge(*r.BFsd)
+	}
+	if r.BFpe != nil {
+		s.bfpe.Merge(*r.BFpe)
+	}
+	s.n++
+}
+
+// Result returns the current population estimate from everything merged in
+// so far. Scraper has no locking: calling Result from another goroutine while
+// a Scrape or StreamScrape is still merging responses is a data race.
+// StreamScrape sends these partial estimates on its channel as they change.
+func (s *Scraper) Result() Result {
+	return Result{
+		Responses: s.n,
+		Seeds:     s.bfsd.EstimatedSize(),
+		Peers:     s.bfpe.EstimatedSize(),
+	}
+}
+
+// Scrape performs a scraping get_peers lookup for the Scraper's infohash,
+// merging every responding node's Bloom filters, and returns the final
+// estimate once the lookup converges or ctx is done.
+func (s *Scraper) Scrape(ctx context.Context) (Result, error) {
+	responses, err := s.lookup.GetPeersScrape(ctx, s.infoHash)
+	if err != nil {
+		return Result{}, err
+	}
+	for r := range responses {
+		s.add(r)
+	}
+	return s.Result(), ctx.Err()
+}
+
+// StreamScrape is like Scrape, but sends a partial Result on the returned
+// channel after every node reply, so a caller can show a live swarm-size
+// estimate without waiting for the lookup to converge. The channel is
+// closed once the lookup finishes or ctx is done.
+func (s *Scraper) StreamScrape(ctx context.Context) (<-chan Result, error) {
+	responses, err := s.lookup.GetPeersScrape(ctx, s.infoHash)
+	if err != nil {
+		return nil, err
+	}
+	updates := make(chan Result)
+	go func() {
+		defer close(updates)
+		for {
+			select {
+			case r, ok := <-responses:
+				if !ok {
+					return
+				}
+				s.add(r)
+				select {
+				case updates <- s.Result():
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return updates, nil
+}
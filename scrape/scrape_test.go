@@ -0,0 +1,121 @@
+package scrape
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yarikk/dht/krpc"
+)
+
+type fakeLookup struct {
+	returns []krpc.Return
+}
+
+func (l *fakeLookup) GetPeersScrape(ctx context.Context, infoHash krpc.ID) (<-chan krpc.Return, error) {
+	ch := make(chan krpc.Return, len(l.returns))
+	for _, r := range l.returns {
+		ch <- r
+	}
+	close(ch)
+	return ch, nil
+}
+
+func filterWithInfohashes(ns ...byte) krpc.ScrapeBloomFilter {
+	var f krpc.ScrapeBloomFilter
+	for _, n := range ns {
+		var ih [20]byte
+		ih[0] = n
+		f.Set(ih)
+	}
+	return f
+}
+
+func TestScraperMergesFiltersAcrossNodes(t *testing.T) {
+	bfsd1 := filterWithInfohashes(1, 2, 3)
+	bfpe1 := filterWithInfohashes(1, 2, 3, 4)
+	bfsd2 := filterWithInfohashes(3, 4, 5)
+	bfpe2 := filterWithInfohashes(3, 4, 5, 6)
+
+	lookup := &fakeLookup{returns: []krpc.Return{
+		{BFsd: &bfsd1, BFpe: &bfpe1},
+		{BFsd: &bfsd2, BFpe: &bfpe2},
+	}}
+	s := NewScraper(lookup, krpc.ID{})
+	result, err := s.Scrape(context.Background())
+	if err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+	if result.Responses != 2 {
+		t.Fatalf("Responses = %d, want 2", result.Responses)
+	}
+
+	var wantSeeds, wantPeers krpc.ScrapeBloomFilter
+	wantSeeds.Merge(bfsd1)
+	wantSeeds.Merge(bfsd2)
+	wantPeers.Merge(bfpe1)
+	wantPeers.Merge(bfpe2)
+	if result.Seeds != wantSeeds.EstimatedSize() {
+		t.Fatalf("Seeds = %d, want %d", result.Seeds, wantSeeds.EstimatedSize())
+	}
+	if result.Peers != wantPeers.EstimatedSize() {
+		t.Fatalf("Peers = %d, want %d", result.Peers, wantPeers.EstimatedSize())
+	}
+}
+
+func TestScraperIgnoresResponsesWithoutFilters(t *testing.T) {
+	lookup := &fakeLookup{returns: []krpc.Return{{}}}
+	s := NewScraper(lookup, krpc.ID{})
+	result, err := s.Scrape(context.Background())
+	if err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+	if result.Responses != 0 {
+		t.Fatalf("Responses = %d, want 0 for a node that didn't support BEP 33", result.Responses)
+	}
+}
+
+func TestTwoScrapersForDifferentInfohashesDontShareState(t *testing.T) {
+	bfsd1 := filterWithInfohashes(1, 2)
+	bfsd2 := filterWithInfohashes(10, 11, 12)
+
+	var ih1, ih2 krpc.ID
+	ih1[0], ih2[0] = 1, 2
+
+	s1 := NewScraper(&fakeLookup{returns: []krpc.Return{{BFsd: &bfsd1}}}, ih1)
+	s2 := NewScraper(&fakeLookup{returns: []krpc.Return{{BFsd: &bfsd2}}}, ih2)
+
+	r1, err := s1.Scrape(context.Background())
+	if err != nil {
+		t.Fatalf("s1.Scrape: %v", err)
+	}
+	r2, err := s2.Scrape(context.Background())
+	if err != nil {
+		t.Fatalf("s2.Scrape: %v", err)
+	}
+
+	if r1.Responses != 1 || r2.Responses != 1 {
+		t.Fatalf("each Scraper should only have merged its own node's response, got r1=%+v r2=%+v", r1, r2)
+	}
+}
+
+func TestScraperStreamScrapeSendsPartialResults(t *testing.T) {
+	bfsd1 := filterWithInfohashes(1)
+	bfsd2 := filterWithInfohashes(2)
+	lookup := &fakeLookup{returns: []krpc.Return{{BFsd: &bfsd1}, {BFsd: &bfsd2}}}
+	s := NewScraper(lookup, krpc.ID{})
+
+	updates, err := s.StreamScrape(context.Background())
+	if err != nil {
+		t.Fatalf("StreamScrape: %v", err)
+	}
+	var results []Result
+	for r := range updates {
+		results = append(results, r)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d updates, want 2", len(results))
+	}
+	if results[0].Responses != 1 || results[1].Responses != 2 {
+		t.Fatalf("updates should report a running count, got %+v", results)
+	}
+}